@@ -1,16 +1,34 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/kelseyhightower/envconfig"
 
 	log "github.com/buzzfeed/sso/internal/pkg/logging"
+	"github.com/buzzfeed/sso/internal/pkg/tlsutil"
 	"github.com/buzzfeed/sso/internal/proxy"
 )
 
+// httpsRedirectHandler answers every request with a 301 redirect to the
+// same host and path on httpsPort, for use on the plaintext listener when
+// TLS is configured and opts.RedirectHTTPToHTTPS is set. It never falls
+// through to the real handler, so no request is ever served in the clear.
+func httpsRedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.URL.Hostname()
+		if host == "" {
+			host = strings.Split(req.Host, ":")[0]
+		}
+		target := fmt.Sprintf("https://%s:%d%s", host, httpsPort, req.URL.RequestURI())
+		http.Redirect(w, req, target, http.StatusMovedPermanently)
+	})
+}
+
 func init() {
 	log.SetServiceName("sso-proxy")
 }
@@ -19,6 +37,13 @@ func main() {
 	logger := log.NewLogEntry()
 
 	opts := proxy.NewOptions()
+	if configFile := os.Getenv(proxy.ConfigFileEnvVar); configFile != "" {
+		if err := proxy.LoadConfigFile(configFile, opts); err != nil {
+			logger.Error(err, "error loading config file")
+			os.Exit(1)
+		}
+	}
+
 	err := envconfig.Process("", opts)
 	if err != nil {
 		logger.Error(err, "error parsing env vars into options")
@@ -43,11 +68,40 @@ func main() {
 		opts.StatsdClient,
 	)
 
+	plaintextHandler := loggingHandler
+	if (opts.TLSCertFile != "" || opts.TLSCertDir != "") && opts.RedirectHTTPToHTTPS {
+		plaintextHandler = httpsRedirectHandler(opts.HTTPSPort)
+	}
+
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%d", opts.Port),
 		ReadTimeout:  opts.TCPReadTimeout,
 		WriteTimeout: opts.TCPWriteTimeout,
-		Handler:      loggingHandler,
+		Handler:      plaintextHandler,
+	}
+
+	if opts.TLSCertFile != "" || opts.TLSCertDir != "" {
+		httpsServer := &http.Server{
+			Addr:         fmt.Sprintf(":%d", opts.HTTPSPort),
+			ReadTimeout:  opts.TCPReadTimeout,
+			WriteTimeout: opts.TCPWriteTimeout,
+			Handler:      loggingHandler,
+		}
+
+		if opts.TLSCertDir != "" {
+			getCertificate, err := tlsutil.GetCertificateFunc(opts.TLSCertDir)
+			if err != nil {
+				logger.Error(err, "error loading tls-cert-dir")
+				os.Exit(1)
+			}
+			httpsServer.TLSConfig = &tls.Config{GetCertificate: getCertificate}
+		}
+
+		go func() {
+			logger.Fatal(s.ListenAndServe())
+		}()
+		logger.Fatal(httpsServer.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile))
+		return
 	}
 
 	logger.Fatal(s.ListenAndServe())
@@ -2,8 +2,10 @@ package auth
 
 import (
 	"crypto"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"html/template"
 	"net/http"
 	"net/url"
 	"os"
@@ -17,21 +19,39 @@ import (
 	"github.com/spf13/viper"
 )
 
+// configFileEnvVar is the env var that points at an optional
+// TOML/YAML/JSON config file. Values it sets are layered underneath env
+// vars, which always take precedence. There is no command-line flag for it.
+const configFileEnvVar = "SSO_CONFIG_FILE"
+
 // Options are config options that can be set by environment variables
 // RedirectURL - string - the OAuth Redirect URL. ie: \"https://internalapp.yourcompany.com/oauth2/callback\
 // ClientID - string - the OAuth ClientID ie "123456.apps.googleusercontent.com"
 // ClientSecret string - the OAuth Client Secret
 // OrgName - string - if using Okta as the provider, the Okta domain to use
+// OIDCIssuerURL - string - if using the generic oidc provider, the issuer URL to discover endpoints from
+// OIDCJWKSURL - string - if using the generic oidc provider, overrides the jwks_uri found via discovery
+// SkipOIDCDiscovery - bool - if using the generic oidc provider, skip discovery and require SignInURL/RedeemURL/ProfileURL/OIDCJWKSURL to be set directly
+// GitHubOrg - string - if using the github provider, restrict access to members of this org
+// GitHubTeam - string - if using the github provider and GitHubOrg is set, further restrict access to members of this team within GitHubOrg
+//
+// Options may also be set via a TOML/YAML/JSON file pointed to by the
+// SSO_CONFIG_FILE env var. File values are overridden by any equivalent env
+// var. The file is read once at startup; later edits to it are not picked up.
 // ProxyClientID - string - the client id that matches the sso proxy client id
 // ProxyClientSecret - string - the client secret that matches the sso proxy client secret
 // Host - string - The host that is in the header that is required on incoming requests
 // Port - string - Port to listen on
 // EmailDomains - []string - authenticate emails with the specified domain (may be given multiple times). Use * to authenticate any email
 // EmailAddresses - []string - authenticate emails with the specified email address (may be given multiple times). Use * to authenticate any email
+// AuthenticatedEmailsFile - string - path to a newline-delimited (# comments allowed) file of exact email addresses; the auth package only stores this value and does not load the file or consult it when authenticating emails
 // ProxyRootDomains - []string - only redirect to specified proxy domains (may be given multiple times)
+// WhitelistDomains - []string - additional domains post-auth redirects may target beyond ProxyRootDomains (e.g. ".example.com", "foo.example.com:8443"), checked by IsValidRedirect
+// RedirectRequireHTTPS - bool (default true) - require an https scheme for WhitelistDomains redirects
 // GoogleAdminEmail - string - the google admin to impersonate for api calls
 // GoogleServiceAccountJSON - string - the path to the service account json credentials
 // Footer - string custom footer string. Use \"-\" to disable default footer.
+// CustomTemplatesDir - string - directory containing sign_in.html/error.html/sign_out.html overrides layered on top of the compiled-in templates
 // CookieSecret - string - the seed string for secure cookies (optionally base64 encoded)
 // CookieDomain - string - an optional cookie domain to force cookies to (ie: .yourcompany.com)*
 // CookieExpire - duration - expire timeframe for cookie, defaults at 168 hours
@@ -44,6 +64,7 @@ import (
 // GroupsCacheRefreshTTL - time.Duratoin - cache TTL for the groups fillcache mechanism used to preemptively fill group caches
 // PassHostHeader - bool - pass the request Host Header to upstream (default true)
 // SkipProviderButton - bool - if true, will skip sign-in-page to directly reach the next step: oauth/start
+// DisplayHtpasswdForm - bool - if true, renders a username/password form on the sign-in page as an alternative to the provider button, for use with proxy.Options.HtpasswdFile
 // PassUserHeaders - bool (default true) - pass X-Forwarded-User and X-Forwarded-Email information to upstream
 // SetXAuthRequest - set X-Auth-Request-User and X-Auth-Request-Email response headers (useful in Nginx auth_request mode)
 // Provider - provider name
@@ -58,6 +79,11 @@ import (
 // RequestLogging - bool to log requests
 // StatsdPort - port where statsd client listens
 // StatsdHost - host where statsd client listens
+// TLSCertFile - string - path to a TLS certificate to terminate TLS natively
+// TLSKeyFile - string - path to the private key matching TLSCertFile
+// TLSCertDir - string - path to a directory of cert/key pairs (each <name>.crt with a matching <name>.key) to serve via SNI, as an alternative to TLSCertFile/TLSKeyFile
+// HTTPSPort - int - port to serve TLS on when TLSCertFile/TLSKeyFile or TLSCertDir are set; Port continues to serve plain HTTP unless RedirectHTTPToHTTPS is set
+// RedirectHTTPToHTTPS - bool (default false) - when TLS is configured, answer every request on Port with a 301 redirect to the same path on HTTPSPort instead of serving it in the clear
 type Options struct {
 	RedirectURL       string `mapstructure:"redirect_url" `
 	ClientID          string `mapstructure:"client_id"`
@@ -68,17 +94,29 @@ type Options struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
 
-	EmailDomains     []string `mapstructure:"sso_email_domain"`
-	EmailAddresses   []string `mapstructure:"sso_email_addresses"`
-	ProxyRootDomains []string `mapstructure:"proxy_root_domain"`
+	EmailDomains            []string `mapstructure:"sso_email_domain"`
+	EmailAddresses          []string `mapstructure:"sso_email_addresses"`
+	AuthenticatedEmailsFile string   `mapstructure:"authenticated_emails_file"`
+	ProxyRootDomains        []string `mapstructure:"proxy_root_domain"`
+	WhitelistDomains        []string `mapstructure:"whitelist_domains"`
+	RedirectRequireHTTPS    bool     `mapstructure:"redirect_require_https"`
 
 	GoogleAdminEmail         string `mapstructure:"google_admin_email"`
 	GoogleServiceAccountJSON string `mapstructure:"google_service_account_json"`
 
 	OrgURL string `mapstructure:"okta_org_url"`
 
+	OIDCIssuerURL     string `mapstructure:"oidc_issuer_url"`
+	OIDCJWKSURL       string `mapstructure:"oidc_jwks_url"`
+	SkipOIDCDiscovery bool   `mapstructure:"skip_oidc_discovery"`
+
+	GitHubOrg  string `mapstructure:"github_org"`
+	GitHubTeam string `mapstructure:"github_team"`
+
 	Footer string `mapstructure:"footer"`
 
+	CustomTemplatesDir string `mapstructure:"custom_templates_dir"`
+
 	CookieName     string        `mapstructure:"cookie_name"`
 	CookieSecret   string        `mapstructure:"cookie_secret"`
 	CookieDomain   string        `mapstructure:"cookie_domain"`
@@ -97,10 +135,11 @@ type Options struct {
 	GroupsCacheRefreshTTL time.Duration `mapstructure:"groups_cache_refresh_ttl"`
 	SessionLifetimeTTL    time.Duration `mapstructure:"session_lifetime_ttl"`
 
-	PassHostHeader     bool `mapstructure:"pass_host_header"`
-	SkipProviderButton bool `mapstructure:"skip_provider_button"`
-	PassUserHeaders    bool `mapstructure:"pass_user_headers"`
-	SetXAuthRequest    bool `mapstructure:"set_xauthrequest"`
+	PassHostHeader      bool `mapstructure:"pass_host_header"`
+	SkipProviderButton  bool `mapstructure:"skip_provider_button"`
+	PassUserHeaders     bool `mapstructure:"pass_user_headers"`
+	SetXAuthRequest     bool `mapstructure:"set_xauthrequest"`
+	DisplayHtpasswdForm bool `mapstructure:"display_htpasswd_form"`
 
 	// These options allow for other providers besides Google, with potential overrides.
 	Provider         string `mapstructure:"provider"`
@@ -119,10 +158,18 @@ type Options struct {
 	StatsdPort int    `mapstructure:"statsd_port"`
 	StatsdHost string `mapstructure:"statsd_host"`
 
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+	TLSCertDir  string `mapstructure:"tls_cert_dir"`
+	HTTPSPort   int    `mapstructure:"https_port"`
+
+	RedirectHTTPToHTTPS bool `mapstructure:"redirect_http_to_https"`
+
 	// internal values that are set after config validation
 	redirectURL         *url.URL
 	decodedCookieSecret []byte
 	GroupsCacheStopFunc func()
+	templates           *template.Template
 }
 
 // SignatureData represents the data associated with signatures
@@ -159,6 +206,12 @@ func loadVars(overrides map[string]interface{}) (*Options, error) {
 	bindAllOptVars(reflect.TypeOf(&opts).Elem(), "mapstructure")
 	setDefaults()
 
+	if configFile := os.Getenv(configFileEnvVar); configFile != "" {
+		if err := readConfigFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
 	for key, value := range overrides {
 		viper.Set(key, value)
 	}
@@ -204,12 +257,30 @@ func setDefaults() {
 		"provider_server_id":       "default",
 		"approval_prompt":          "force",
 		"request_logging":          true,
+		"redirect_require_https":   true,
 	}
 	for k, v := range defaultVars {
 		viper.SetDefault(k, v)
 	}
 }
 
+// readConfigFile loads configFile (TOML, YAML, or JSON, auto-detected from
+// its extension) into the default viper instance underneath any values
+// already bound from the environment. This is a one-time, startup-only
+// load: Options is unmarshaled from it once in loadVars and handed to
+// callers by value, so there is no mechanism here to observe later edits
+// to the file. Config changes that need to take effect without a restart
+// -- the authenticated-emails allowlist, htpasswd entries -- are handled
+// by their own dedicated file watchers (see options.EmailListValidator
+// and htpasswd.HtpasswdFile), not by this function.
+func readConfigFile(configFile string) error {
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file %q: %s", configFile, err)
+	}
+	return nil
+}
+
 func parseURL(toParse string, urltype string, msgs []string) (*url.URL, []string) {
 	parsed, err := url.Parse(toParse)
 	if err != nil {
@@ -286,6 +357,18 @@ func (o *Options) Validate() error {
 
 	msgs = validateCookieName(o, msgs)
 
+	msgs = validateTLSSettings(o, msgs)
+
+	if o.CustomTemplatesDir != "" {
+		if err := validateCustomTemplatesDir(o.CustomTemplatesDir); err != nil {
+			msgs = append(msgs, err.Error())
+		} else if templates, err := loadCustomTemplates(o.CustomTemplatesDir, defaultTemplates()); err != nil {
+			msgs = append(msgs, err.Error())
+		} else {
+			o.templates = templates
+		}
+	}
+
 	if o.StatsdHost == "" {
 		msgs = append(msgs, "missing setting: no host specified for statsd metrics collections")
 	}
@@ -301,6 +384,18 @@ func (o *Options) Validate() error {
 	return nil
 }
 
+// Templates returns the compiled-in sign_in.html/error.html/sign_out.html
+// templates, overlaid with any CustomTemplatesDir overrides Validate
+// loaded. Validate must be called first; before that, or when
+// CustomTemplatesDir is unset, this returns the compiled-in defaults
+// unmodified.
+func (o *Options) Templates() *template.Template {
+	if o.templates == nil {
+		return defaultTemplates()
+	}
+	return o.templates
+}
+
 func validateEndpoints(o *Options, msgs []string) []string {
 	_, msgs = parseURL(o.SignInURL, "signin", msgs)
 	_, msgs = parseURL(o.RedeemURL, "redeem", msgs)
@@ -311,6 +406,36 @@ func validateEndpoints(o *Options, msgs []string) []string {
 	return msgs
 }
 
+// validateTLSSettings checks that TLSCertFile/TLSKeyFile (or TLSCertDir)
+// exist and parse, and that HTTPSPort is set whenever native TLS
+// termination is configured.
+func validateTLSSettings(o *Options, msgs []string) []string {
+	if o.TLSCertFile == "" && o.TLSKeyFile == "" && o.TLSCertDir == "" {
+		return msgs
+	}
+
+	if o.TLSCertFile != "" || o.TLSKeyFile != "" {
+		if o.TLSCertFile == "" || o.TLSKeyFile == "" {
+			return append(msgs, "tls-cert-file and tls-key-file must both be set")
+		}
+		if _, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile); err != nil {
+			msgs = append(msgs, fmt.Sprintf("error loading tls-cert-file/tls-key-file: %s", err))
+		}
+	}
+
+	if o.TLSCertDir != "" {
+		if _, err := loadCertsFromDir(o.TLSCertDir); err != nil {
+			msgs = append(msgs, fmt.Sprintf("error loading tls-cert-dir %q: %s", o.TLSCertDir, err))
+		}
+	}
+
+	if o.HTTPSPort == 0 {
+		msgs = append(msgs, "missing setting: https-port is required when tls-cert-file/tls-key-file or tls-cert-dir are set")
+	}
+
+	return msgs
+}
+
 func validateCookieName(o *Options, msgs []string) []string {
 	cookie := &http.Cookie{Name: o.CookieName}
 	if cookie.String() == "" {
@@ -372,6 +497,24 @@ func newProvider(o *Options) (providers.Provider, error) {
 
 		groupsCache := providers.NewGroupCache(oktaProvider, o.GroupCacheProviderTTL, oktaProvider.StatsdClient, tags)
 		singleFlightProvider = providers.NewSingleFlightProvider(groupsCache)
+	case providers.OIDCProviderName:
+		if o.OIDCIssuerURL == "" {
+			return nil, fmt.Errorf("missing setting: oidc-issuer-url")
+		}
+		oidcProvider, err := providers.NewOIDCProvider(p, o.OIDCIssuerURL, o.OIDCJWKSURL, o.SkipOIDCDiscovery)
+		if err != nil {
+			return nil, err
+		}
+		singleFlightProvider = providers.NewSingleFlightProvider(oidcProvider)
+	case providers.GitHubProviderName:
+		githubProvider, err := providers.NewGitHubProvider(p, o.GitHubOrg, o.GitHubTeam)
+		if err != nil {
+			return nil, err
+		}
+		tags := []string{"provider:github"}
+
+		groupsCache := providers.NewGroupCache(githubProvider, o.GroupCacheProviderTTL, githubProvider.StatsdClient, tags)
+		singleFlightProvider = providers.NewSingleFlightProvider(groupsCache)
 	default:
 		return nil, fmt.Errorf("unimplemented provider: %q", o.Provider)
 	}
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/buzzfeed/sso/internal/pkg/sessions"
+)
+
+// GitHubProviderName identifies the GitHub OAuth2 provider.
+const GitHubProviderName = "github"
+
+// GitHubProvider authenticates against GitHub's OAuth2 endpoints and,
+// when GitHubOrg (and optionally GitHubTeam) are configured, gates access
+// on org/team membership. Team membership is folded into the groups list
+// returned from ValidateGroup so the existing sso-proxy allowed_groups
+// upstream config works unmodified.
+type GitHubProvider struct {
+	*ProviderData
+
+	Org  string
+	Team string
+}
+
+// NewGitHubProvider returns a GitHubProvider wired to github.com's OAuth2
+// and API endpoints, restricted (if org is non-empty) to members of org,
+// and (if team is also non-empty) further restricted to members of that
+// team within org.
+func NewGitHubProvider(p *ProviderData, org, team string) (*GitHubProvider, error) {
+	if p.ProviderName == "" {
+		p.ProviderName = "GitHub"
+	}
+	if p.SignInURL.String() == "" {
+		p.SignInURL = &url.URL{Scheme: "https", Host: "github.com", Path: "/login/oauth/authorize"}
+	}
+	if p.RedeemURL.String() == "" {
+		p.RedeemURL = &url.URL{Scheme: "https", Host: "github.com", Path: "/login/oauth/access_token"}
+	}
+	if p.ProfileURL.String() == "" {
+		p.ProfileURL = &url.URL{Scheme: "https", Host: "api.github.com", Path: "/user"}
+	}
+	if p.Scope == "" {
+		p.Scope = "user:email read:org"
+	}
+
+	return &GitHubProvider{ProviderData: p, Org: org, Team: team}, nil
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// GetUserProfile resolves the authenticated user's verified primary email
+// via /user/emails, since GitHub's /user response omits addresses the
+// user has marked private.
+func (p *GitHubProvider) GetUserProfile(session *sessions.SessionState) (string, error) {
+	req, err := http.NewRequest("GET", "https://api.github.com/user/emails", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", session.AccessToken))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got %d fetching /user/emails", resp.StatusCode)
+	}
+
+	var emails []githubEmail
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return "", err
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, nil
+		}
+	}
+	return "", fmt.Errorf("no verified primary email found for user")
+}
+
+// ValidateGroup checks login's (the GitHub username, not email)
+// membership in Org and, when Team is set, Team within Org. accessToken
+// must be the user's own OAuth token (or a configured service token with
+// equivalent read:org access) -- GitHub's membership-check endpoints
+// require an authenticated caller for anything but fully public
+// membership, so calling them unauthenticated returns 404 for private
+// members and locks out every legitimate user. ValidateGroup returns the
+// group strings the sso-proxy allowed_groups upstream config matches
+// against, alongside whether login is allowed at all.
+func (p *GitHubProvider) ValidateGroup(login, accessToken string) ([]string, bool) {
+	if p.Org == "" {
+		return nil, true
+	}
+
+	member, err := p.isOrgMember(login, accessToken)
+	if err != nil || !member {
+		return nil, false
+	}
+
+	groups := []string{p.Org}
+	if p.Team == "" {
+		return groups, true
+	}
+
+	teamMember, err := p.isTeamMember(login, accessToken)
+	if err != nil || !teamMember {
+		return groups, false
+	}
+	groups = append(groups, fmt.Sprintf("%s/%s", p.Org, p.Team))
+
+	return groups, true
+}
+
+func (p *GitHubProvider) isOrgMember(login, accessToken string) (bool, error) {
+	return p.checkMembership(fmt.Sprintf("https://api.github.com/orgs/%s/members/%s", p.Org, login), accessToken)
+}
+
+func (p *GitHubProvider) isTeamMember(login, accessToken string) (bool, error) {
+	return p.checkMembership(fmt.Sprintf("https://api.github.com/orgs/%s/teams/%s/memberships/%s", p.Org, p.Team, login), accessToken)
+}
+
+// checkMembership calls a GitHub membership-check endpoint as accessToken,
+// which responds 204 for an active member, 404 otherwise.
+func (p *GitHubProvider) checkMembership(membershipURL, accessToken string) (bool, error) {
+	req, err := http.NewRequest("GET", membershipURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", accessToken))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("got %d checking membership at %s", resp.StatusCode, membershipURL)
+	}
+}
@@ -0,0 +1,343 @@
+package providers
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/buzzfeed/sso/internal/pkg/sessions"
+)
+
+// OIDCProviderName identifies the generic OpenID Connect provider. Unlike
+// Google and Okta, it has no hard-coded endpoints: it is configured with an
+// issuer URL and discovers everything else, so it works unmodified against
+// Keycloak, Auth0, Dex, Azure AD, or any other OIDC-compliant IdP.
+const OIDCProviderName = "oidc"
+
+// oidcDiscoveryDoc is the subset of an issuer's
+// /.well-known/openid-configuration document that sso needs in order to
+// configure itself.
+type oidcDiscoveryDoc struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jwkSet is the JSON Web Key Set document served from an OIDC provider's
+// jwks_uri, used to verify the signature on returned id_tokens.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// OIDCProvider implements the Provider interface against any OpenID Connect
+// compliant identity provider.
+type OIDCProvider struct {
+	*ProviderData
+
+	IssuerURL string
+
+	jwksURL string
+	keys    map[string]*rsa.PublicKey
+}
+
+// NewOIDCProvider discovers issuerURL's endpoints (unless skipDiscovery is
+// set, in which case p's SignInURL, RedeemURL, and ProfileURL must already
+// be populated by the caller) and returns an OIDCProvider ready to
+// authenticate users. jwksURLOverride, if non-empty, is used instead of the
+// jwks_uri found in the discovery document.
+func NewOIDCProvider(p *ProviderData, issuerURL, jwksURLOverride string, skipDiscovery bool) (*OIDCProvider, error) {
+	if p.ProviderName == "" {
+		p.ProviderName = "OIDC"
+	}
+
+	provider := &OIDCProvider{ProviderData: p, IssuerURL: issuerURL}
+
+	jwksURL := jwksURLOverride
+	if !skipDiscovery {
+		doc, err := discoverOIDCConfig(issuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("error discovering oidc configuration: %s", err)
+		}
+
+		if p.SignInURL.String() == "" {
+			if p.SignInURL, err = url.Parse(doc.AuthorizationEndpoint); err != nil {
+				return nil, fmt.Errorf("error parsing authorization_endpoint: %s", err)
+			}
+		}
+		if p.RedeemURL.String() == "" {
+			if p.RedeemURL, err = url.Parse(doc.TokenEndpoint); err != nil {
+				return nil, fmt.Errorf("error parsing token_endpoint: %s", err)
+			}
+		}
+		if p.ProfileURL.String() == "" {
+			if p.ProfileURL, err = url.Parse(doc.UserinfoEndpoint); err != nil {
+				return nil, fmt.Errorf("error parsing userinfo_endpoint: %s", err)
+			}
+		}
+		if p.RevokeURL.String() == "" && doc.RevocationEndpoint != "" {
+			if p.RevokeURL, err = url.Parse(doc.RevocationEndpoint); err != nil {
+				return nil, fmt.Errorf("error parsing revocation_endpoint: %s", err)
+			}
+		}
+		if jwksURL == "" {
+			jwksURL = doc.JWKSURI
+		}
+	}
+
+	if jwksURL == "" {
+		return nil, fmt.Errorf("no jwks_uri discovered or configured for issuer %q", issuerURL)
+	}
+	provider.jwksURL = jwksURL
+
+	keys, err := fetchJWKS(jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jwks: %s", err)
+	}
+	provider.keys = keys
+
+	return provider, nil
+}
+
+func discoverOIDCConfig(issuerURL string) (*oidcDiscoveryDoc, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/.well-known/openid-configuration", issuerURL))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %d fetching well-known configuration", resp.StatusCode)
+	}
+
+	var doc oidcDiscoveryDoc
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func fetchJWKS(jwksURL string) (map[string]*rsa.PublicKey, error) {
+	resp, err := http.Get(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %d fetching jwks", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pubKey, err := jwkToRSAPublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing key %q: %s", k.Kid, err)
+		}
+		keys[k.Kid] = pubKey
+	}
+	return keys, nil
+}
+
+func jwkToRSAPublicKey(k jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+// Redeem exchanges the OAuth redirect code for an access_token,
+// refresh_token, and id_token, and verifies the id_token's signature
+// against the issuer's JWKS before building a SessionState from its claims.
+func (p *OIDCProvider) Redeem(redirectURL, code string) (*sessions.SessionState, error) {
+	session, err := p.ProviderData.Redeem(redirectURL, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.IDToken != "" {
+		if err := p.verifyIDToken(session.IDToken); err != nil {
+			return nil, fmt.Errorf("error verifying id_token: %s", err)
+		}
+	}
+
+	return session, nil
+}
+
+// RefreshSessionIfNeeded uses the session's refresh_token to transparently
+// renew its access_token once it expires, so long as the session is still
+// within its overall SessionLifetimeTTL. It returns whether the session was
+// refreshed.
+func (p *OIDCProvider) RefreshSessionIfNeeded(session *sessions.SessionState) (bool, error) {
+	if session == nil || !session.IsExpired() {
+		return false, nil
+	}
+	if session.RefreshToken == "" {
+		return false, fmt.Errorf("no refresh_token available to refresh session")
+	}
+	if p.SessionLifetimeTTL > 0 && time.Now().After(session.CreatedAt.Add(p.SessionLifetimeTTL)) {
+		return false, fmt.Errorf("session exceeded session_lifetime_ttl")
+	}
+
+	refreshed, err := p.ProviderData.RefreshSessionIfNeeded(session)
+	if err != nil {
+		return false, err
+	}
+	return refreshed, nil
+}
+
+// ValidateSessionState verifies that the session's access_token (and, when
+// present, its id_token) is still valid against the provider.
+func (p *OIDCProvider) ValidateSessionState(session *sessions.SessionState) bool {
+	if session.IDToken != "" {
+		if err := p.verifyIDToken(session.IDToken); err != nil {
+			return false
+		}
+	}
+	return p.ProviderData.ValidateSessionState(session)
+}
+
+// GetUserProfile fetches the userinfo endpoint for the authenticated user.
+func (p *OIDCProvider) GetUserProfile(session *sessions.SessionState) (string, error) {
+	return p.ProviderData.GetUserProfile(session)
+}
+
+// idTokenClaims is the subset of an id_token's payload that verifyIDToken
+// checks. aud is accepted as either a single string or an array, per the
+// JWT spec.
+type idTokenClaims struct {
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt int64           `json:"exp"`
+	NotBefore int64           `json:"nbf"`
+}
+
+func (c *idTokenClaims) audiences() ([]string, error) {
+	if len(c.Audience) == 0 {
+		return nil, nil
+	}
+
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(c.Audience, &multiple); err != nil {
+		return nil, fmt.Errorf("error parsing aud claim: %s", err)
+	}
+	return multiple, nil
+}
+
+// verifyIDToken checks idToken's signature against the keys discovered
+// from the provider's JWKS endpoint, then validates the standard exp,
+// nbf, iss, and aud claims against IssuerURL and ClientID. Callers must
+// not trust the token without both checks passing.
+func (p *OIDCProvider) verifyIDToken(idToken string) error {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed id_token: expected 3 segments, got %d", len(parts))
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("error decoding id_token header: %s", err)
+	}
+	var hdr struct {
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &hdr); err != nil {
+		return fmt.Errorf("error parsing id_token header: %s", err)
+	}
+
+	key, ok := p.keys[hdr.Kid]
+	if !ok {
+		return fmt.Errorf("unknown signing key %q", hdr.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("error decoding id_token signature: %s", err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("id_token signature verification failed: %s", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("error decoding id_token payload: %s", err)
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("error parsing id_token payload: %s", err)
+	}
+
+	return p.validateClaims(claims)
+}
+
+// validateClaims enforces the exp, nbf, iss, and aud claims a
+// signature-only check would otherwise skip: iss must match the issuer
+// sso discovered/was configured with, aud must include our ClientID, and
+// the token must be within its exp/nbf validity window.
+func (p *OIDCProvider) validateClaims(claims idTokenClaims) error {
+	now := time.Now()
+
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0)) {
+		return fmt.Errorf("id_token is expired")
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("id_token is not valid yet")
+	}
+	if claims.Issuer != p.IssuerURL {
+		return fmt.Errorf("id_token has unexpected issuer %q, want %q", claims.Issuer, p.IssuerURL)
+	}
+
+	auds, err := claims.audiences()
+	if err != nil {
+		return err
+	}
+	for _, aud := range auds {
+		if aud == p.ClientID {
+			return nil
+		}
+	}
+	return fmt.Errorf("id_token audience %v does not include client_id %q", auds, p.ClientID)
+}
@@ -0,0 +1,158 @@
+package providers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIDToken signs a minimal JWT-shaped id_token with priv under kid, so
+// verifyIDToken can be exercised end-to-end against a real signature
+// without standing up a JWKS endpoint.
+func testIDToken(t *testing.T, priv *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"kid": kid, "alg": "RS256"}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("error marshaling header: %s", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("error marshaling claims: %s", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("error signing id_token: %s", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestOIDCProvider(t *testing.T, priv *rsa.PrivateKey, kid string) *OIDCProvider {
+	t.Helper()
+	return &OIDCProvider{
+		ProviderData: &ProviderData{ClientID: "test-client-id"},
+		IssuerURL:    "https://issuer.example.com",
+		keys:         map[string]*rsa.PublicKey{kid: &priv.PublicKey},
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating rsa key: %s", err)
+	}
+	const kid = "test-kid"
+
+	now := time.Now()
+	validClaims := map[string]interface{}{
+		"iss": "https://issuer.example.com",
+		"aud": "test-client-id",
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+
+	tests := []struct {
+		name      string
+		claims    map[string]interface{}
+		kid       string
+		tamperSig bool
+		wantErr   string
+	}{
+		{
+			name:   "valid token",
+			claims: validClaims,
+			kid:    kid,
+		},
+		{
+			name: "expired",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com",
+				"aud": "test-client-id",
+				"exp": now.Add(-time.Hour).Unix(),
+			},
+			kid:     kid,
+			wantErr: "expired",
+		},
+		{
+			name: "not yet valid",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com",
+				"aud": "test-client-id",
+				"exp": now.Add(time.Hour).Unix(),
+				"nbf": now.Add(time.Hour).Unix(),
+			},
+			kid:     kid,
+			wantErr: "not valid yet",
+		},
+		{
+			name: "forged issuer",
+			claims: map[string]interface{}{
+				"iss": "https://attacker.example.com",
+				"aud": "test-client-id",
+				"exp": now.Add(time.Hour).Unix(),
+			},
+			kid:     kid,
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			claims: map[string]interface{}{
+				"iss": "https://issuer.example.com",
+				"aud": "someone-elses-client-id",
+				"exp": now.Add(time.Hour).Unix(),
+			},
+			kid:     kid,
+			wantErr: "does not include client_id",
+		},
+		{
+			name:    "unknown kid",
+			claims:  validClaims,
+			kid:     "no-such-kid",
+			wantErr: "unknown signing key",
+		},
+		{
+			name:      "bad signature",
+			claims:    validClaims,
+			kid:       kid,
+			tamperSig: true,
+			wantErr:   "signature verification failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestOIDCProvider(t, priv, kid)
+			idToken := testIDToken(t, priv, tt.kid, tt.claims)
+			if tt.tamperSig {
+				idToken = idToken[:len(idToken)-4] + "abcd"
+			}
+
+			err := p.verifyIDToken(idToken)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %s", tt.wantErr, err)
+			}
+		})
+	}
+}
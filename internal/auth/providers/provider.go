@@ -0,0 +1,25 @@
+package providers
+
+// GroupValidator is implemented by providers whose upstream IdP supports
+// org/team-style group membership (GitHub, and eventually Okta) and is
+// what NewGroupCache wraps to cache membership lookups.
+//
+// ValidateGroup must authenticate as the user being checked, passing
+// their own OAuth accessToken, rather than sso's own credentials: GitHub
+// (and Okta) require an authenticated caller for anything but fully
+// public membership, so an unauthenticated or service-credentialed check
+// would 404 for private members and lock out every legitimate user.
+// ValidateGroup returns the group strings the sso-proxy allowed_groups
+// upstream config matches against, alongside whether login is allowed at
+// all.
+//
+// Google's provider doesn't implement GroupValidator: its group
+// membership comes from the admin-directory API via GroupsCache/
+// PopulateMembers instead of a per-login lookup, so it has no use for
+// this interface. Okta's provider isn't part of this tree snapshot, so
+// GitHubProvider is GroupValidator's only implementation here.
+type GroupValidator interface {
+	ValidateGroup(login, accessToken string) ([]string, bool)
+}
+
+var _ GroupValidator = (*GitHubProvider)(nil)
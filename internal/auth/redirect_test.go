@@ -0,0 +1,61 @@
+package auth
+
+import "testing"
+
+func TestIsValidRedirect(t *testing.T) {
+	o := &Options{
+		WhitelistDomains:     []string{".example.com", "foo.other.com:8443", "bar.other.com"},
+		RedirectRequireHTTPS: true,
+	}
+
+	cases := []struct {
+		name     string
+		redirect string
+		want     bool
+	}{
+		{"subdomain matches leading-dot entry", "https://docs.example.com/path", true},
+		{"deep subdomain matches leading-dot entry", "https://a.b.example.com/path", true},
+		{"bare domain does not match leading-dot entry", "https://example.com/path", false},
+		{"exact host:port match", "https://foo.other.com:8443/path", true},
+		{"host without matching port is rejected", "https://foo.other.com/path", false},
+		{"exact host match", "https://bar.other.com/path", true},
+		{"unlisted host is rejected", "https://evil.com/path", false},
+		{"http is rejected when https is required", "http://bar.other.com/path", false},
+		{"scheme-relative url is rejected", "//evil.com/path", false},
+		{"userinfo-embedded url is rejected", "https://bar.other.com@evil.com/path", false},
+		{"empty redirect is rejected", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := o.IsValidRedirect(tc.redirect); got != tc.want {
+				t.Errorf("IsValidRedirect(%q) = %v, want %v", tc.redirect, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsValidRedirectAllowsHTTPWhenNotRequired(t *testing.T) {
+	o := &Options{
+		WhitelistDomains:     []string{".example.com"},
+		RedirectRequireHTTPS: false,
+	}
+
+	if !o.IsValidRedirect("http://docs.example.com/path") {
+		t.Errorf("expected http redirect to be valid when RedirectRequireHTTPS is false")
+	}
+}
+
+func TestSafeRedirectURL(t *testing.T) {
+	o := &Options{
+		WhitelistDomains:     []string{".example.com"},
+		RedirectRequireHTTPS: true,
+	}
+
+	if got, want := o.SafeRedirectURL("https://docs.example.com/path", "https://default.example.com/"), "https://docs.example.com/path"; got != want {
+		t.Errorf("SafeRedirectURL() = %q, want %q", got, want)
+	}
+	if got, want := o.SafeRedirectURL("https://evil.com/path", "https://default.example.com/"), "https://default.example.com/"; got != want {
+		t.Errorf("SafeRedirectURL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"crypto/tls"
+
+	"github.com/buzzfeed/sso/internal/pkg/tlsutil"
+)
+
+// loadCertsFromDir reads every <name>.crt/<name>.key pair found directly
+// under dir and returns them as parsed certificates.
+func loadCertsFromDir(dir string) ([]tls.Certificate, error) {
+	return tlsutil.LoadCertsFromDir(dir)
+}
+
+// GetCertificateFunc builds a tls.Config.GetCertificate callback over every
+// cert/key pair found in o.TLSCertDir, so a single listener can serve
+// multiple TLS certificates selected by SNI. It falls back to the first
+// certificate found when the ClientHello carries no matching SNI name.
+//
+// Unlike sso-proxy, sso-auth's cmd/sso-auth/main.go isn't part of this
+// tree, so nothing here actually starts a TLS listener: whatever runs the
+// authenticator needs to call GetCertificateFunc/TLSCertFile/TLSKeyFile
+// itself, the same way cmd/sso-proxy/main.go does with these same fields.
+func (o *Options) GetCertificateFunc() (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	return tlsutil.GetCertificateFunc(o.TLSCertDir)
+}
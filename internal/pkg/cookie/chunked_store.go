@@ -0,0 +1,119 @@
+// Package cookie provides a transparent cookie-chunking layer for
+// sessions that exceed the ~4KB per-cookie limit most browsers enforce.
+package cookie
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxSize is the default maximum size, in bytes, of a single cookie's
+// value before ChunkedStore splits it into numbered chunks. 3800 bytes
+// leaves headroom under the common ~4096 byte per-cookie limit for the
+// cookie's other Set-Cookie attributes (domain, path, expires, etc).
+const MaxSize = 3800
+
+// maxChunks bounds how many numbered chunks ClearCookie will expire. It's
+// sized generously above any session this store is expected to produce.
+const maxChunks = 16
+
+// ChunkedStore splits a cookie value across "<name>_0", "<name>_1", ...
+// cookies once it exceeds a configured max size, and reassembles them on
+// read. Values that fit within the max size are written as a single,
+// unsuffixed cookie so unchunked sessions keep working unmodified.
+type ChunkedStore struct {
+	maxSize int
+}
+
+// NewChunkedStore returns a ChunkedStore that splits cookie values larger
+// than maxSize. A maxSize of 0 uses MaxSize.
+func NewChunkedStore(maxSize int) *ChunkedStore {
+	if maxSize <= 0 {
+		maxSize = MaxSize
+	}
+	return &ChunkedStore{maxSize: maxSize}
+}
+
+// SetCookie writes value onto w, using base as the template for every
+// chunk's attributes (domain, path, expiry, secure, httponly).
+func (s *ChunkedStore) SetCookie(w http.ResponseWriter, base *http.Cookie, value string) {
+	s.ClearCookie(w, base)
+
+	if len(value) <= s.maxSize {
+		cookie := cloneCookie(base)
+		cookie.Value = value
+		http.SetCookie(w, cookie)
+		return
+	}
+
+	for i, part := range chunk(value, s.maxSize) {
+		cookie := cloneCookie(base)
+		cookie.Name = chunkName(base.Name, i)
+		cookie.Value = part
+		http.SetCookie(w, cookie)
+	}
+}
+
+// GetCookie reassembles name's value from req's cookies, preferring
+// numbered chunks and falling back to a single unnamed cookie.
+func (s *ChunkedStore) GetCookie(req *http.Request, name string) (string, error) {
+	var parts []string
+	for i := 0; ; i++ {
+		c, err := req.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+		parts = append(parts, c.Value)
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, ""), nil
+	}
+
+	c, err := req.Cookie(name)
+	if err != nil {
+		return "", fmt.Errorf("cookie %q not found", name)
+	}
+	return c.Value, nil
+}
+
+// ClearCookie expires base's unchunked cookie and every numbered chunk of
+// it, so a logout can't leave a stale chunk behind.
+func (s *ChunkedStore) ClearCookie(w http.ResponseWriter, base *http.Cookie) {
+	expire := func(name string) {
+		cookie := cloneCookie(base)
+		cookie.Name = name
+		cookie.Value = ""
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+		http.SetCookie(w, cookie)
+	}
+
+	expire(base.Name)
+	for i := 0; i < maxChunks; i++ {
+		expire(chunkName(base.Name, i))
+	}
+}
+
+func chunkName(name string, i int) string {
+	return fmt.Sprintf("%s_%d", name, i)
+}
+
+func chunk(value string, size int) []string {
+	var chunks []string
+	for len(value) > 0 {
+		if len(value) <= size {
+			chunks = append(chunks, value)
+			break
+		}
+		chunks = append(chunks, value[:size])
+		value = value[size:]
+	}
+	return chunks
+}
+
+func cloneCookie(base *http.Cookie) *http.Cookie {
+	cookie := *base
+	return &cookie
+}
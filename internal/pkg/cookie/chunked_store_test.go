@@ -0,0 +1,106 @@
+package cookie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// jar collapses a recorder's Set-Cookie headers down to the cookies a
+// real browser would be left holding: last value per name, with expired
+// (MaxAge < 0) cookies dropped.
+func jar(rec *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(cookies, c.Name)
+			continue
+		}
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+func requestWithCookies(cookies map[string]*http.Cookie) *http.Request {
+	req := &http.Request{Header: http.Header{}}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+func TestChunkedStoreRoundTripsLargeSession(t *testing.T) {
+	store := NewChunkedStore(MaxSize)
+	base := &http.Cookie{Name: "_sso_auth", Path: "/"}
+
+	value := strings.Repeat("a", 12*1024)
+
+	rec := httptest.NewRecorder()
+	store.SetCookie(rec, base, value)
+
+	cookies := jar(rec)
+	if got := len(cookies); got < 4 {
+		t.Fatalf("expected a 12KB session to be split into multiple chunks, got %d cookies", got)
+	}
+
+	got, err := store.GetCookie(requestWithCookies(cookies), base.Name)
+	if err != nil {
+		t.Fatalf("GetCookie returned error: %s", err)
+	}
+	if got != value {
+		t.Fatalf("round-tripped value did not match: got %d bytes, want %d bytes", len(got), len(value))
+	}
+}
+
+func TestChunkedStoreSingleCookieWhenSmall(t *testing.T) {
+	store := NewChunkedStore(MaxSize)
+	base := &http.Cookie{Name: "_sso_auth", Path: "/"}
+
+	value := "short-session-value"
+
+	rec := httptest.NewRecorder()
+	store.SetCookie(rec, base, value)
+
+	cookies := jar(rec)
+	if len(cookies) != 1 {
+		t.Fatalf("expected exactly one cookie for a small session, got %d", len(cookies))
+	}
+	if _, ok := cookies[base.Name]; !ok {
+		t.Fatalf("expected unsuffixed cookie name %q", base.Name)
+	}
+
+	got, err := store.GetCookie(requestWithCookies(cookies), base.Name)
+	if err != nil {
+		t.Fatalf("GetCookie returned error: %s", err)
+	}
+	if got != value {
+		t.Fatalf("got %q, want %q", got, value)
+	}
+}
+
+func TestChunkedStoreClearCookieExpiresAllChunks(t *testing.T) {
+	store := NewChunkedStore(MaxSize)
+	base := &http.Cookie{Name: "_sso_auth", Path: "/"}
+
+	rec := httptest.NewRecorder()
+	store.SetCookie(rec, base, strings.Repeat("a", 12*1024))
+	chunkCount := len(jar(rec))
+
+	clearRec := httptest.NewRecorder()
+	store.ClearCookie(clearRec, base)
+
+	if got := len(jar(clearRec)); got != 0 {
+		t.Fatalf("expected ClearCookie to leave no live cookies behind, got %d", got)
+	}
+
+	expired := 0
+	for _, c := range clearRec.Result().Cookies() {
+		if c.MaxAge < 0 {
+			expired++
+		}
+	}
+	if expired < chunkCount {
+		t.Fatalf("expected at least %d expired cookies to cover every chunk, got %d", chunkCount, expired)
+	}
+}
@@ -0,0 +1,83 @@
+package htpasswd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeHtpasswdFile(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("error writing htpasswd file: %s", err)
+	}
+	return path
+}
+
+func TestHtpasswdFileValidatesBcryptAndSha(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-pass"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("error generating bcrypt hash: %s", err)
+	}
+
+	contents := "ci-bot:" + string(bcryptHash) + "\n" +
+		"legacy-bot:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n" +
+		"# a comment line and a blank line are ignored\n\n"
+
+	path := writeHtpasswdFile(t, contents)
+
+	h, err := NewHtpasswdFile(path)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile returned error: %s", err)
+	}
+	defer h.Stop()
+
+	if !h.Validate("ci-bot", "bcrypt-pass") {
+		t.Errorf("expected bcrypt password to validate")
+	}
+	if h.Validate("ci-bot", "wrong-pass") {
+		t.Errorf("expected wrong bcrypt password to be rejected")
+	}
+	if !h.Validate("legacy-bot", "password") {
+		t.Errorf("expected sha password to validate")
+	}
+	if h.Validate("unknown-bot", "anything") {
+		t.Errorf("expected unknown user to be rejected")
+	}
+}
+
+func TestHtpasswdFileReloadsOnChange(t *testing.T) {
+	path := writeHtpasswdFile(t, "")
+
+	h, err := NewHtpasswdFile(path)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile returned error: %s", err)
+	}
+	defer h.Stop()
+
+	if h.Validate("new-bot", "secret") {
+		t.Fatalf("expected new-bot not to validate before being added")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("error generating bcrypt hash: %s", err)
+	}
+	if err := os.WriteFile(path, []byte("new-bot:"+string(hash)+"\n"), 0o600); err != nil {
+		t.Fatalf("error rewriting htpasswd file: %s", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if h.Validate("new-bot", "secret") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected new-bot to validate after file was updated")
+}
@@ -0,0 +1,93 @@
+package options
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/buzzfeed/sso/internal/pkg/watchfile"
+)
+
+// EmailListValidator validates against a dynamic, newline-delimited list of
+// exact email addresses loaded from a file (lines beginning with # are
+// comments). The file is watched with watchfile and re-parsed on change
+// under an RWMutex, so operators can add or revoke individual users
+// without redeploying sso-auth or sso-proxy.
+type EmailListValidator struct {
+	mu     sync.RWMutex
+	emails map[string]struct{}
+
+	path    string
+	watcher *watchfile.Watcher
+
+	// onReload, if set, is called after every load attempt with the
+	// resulting email count (or the error that made it fail), so callers
+	// can emit statsd counters and logs without this package depending on
+	// the statsd/logging packages.
+	onReload func(count int, err error)
+}
+
+// NewEmailListValidator reads path and starts watching it for changes.
+func NewEmailListValidator(path string, onReload func(count int, err error)) (*EmailListValidator, error) {
+	v := &EmailListValidator{path: path, onReload: onReload}
+
+	watcher, err := watchfile.New(path, v.reload)
+	if err != nil {
+		return nil, err
+	}
+	v.watcher = watcher
+
+	return v, nil
+}
+
+func (v *EmailListValidator) reload() error {
+	emails, err := parseEmailListFile(v.path)
+	if v.onReload != nil {
+		v.onReload(len(emails), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.emails = emails
+	v.mu.Unlock()
+	return nil
+}
+
+func parseEmailListFile(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	emails := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		emails[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// Valid returns true if email is present in the list as of the most recent
+// successful reload.
+func (v *EmailListValidator) Valid(email string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, ok := v.emails[strings.ToLower(email)]
+	return ok
+}
+
+// Stop stops watching the underlying file for changes.
+func (v *EmailListValidator) Stop() {
+	v.watcher.Stop()
+}
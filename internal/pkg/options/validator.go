@@ -0,0 +1,29 @@
+package options
+
+// Validator decides whether an authenticated email address is allowed to
+// complete sign-in.
+type Validator interface {
+	Valid(email string) bool
+}
+
+// orValidator allows an email through if any of its validators does.
+type orValidator struct {
+	validators []Validator
+}
+
+// NewOrValidator combines validators so that an email is valid if it
+// passes any one of them. It's used to layer the dynamic
+// EmailListValidator on top of the static domain/address validators
+// without operators losing access to either allowlist.
+func NewOrValidator(validators ...Validator) Validator {
+	return &orValidator{validators: validators}
+}
+
+func (v *orValidator) Valid(email string) bool {
+	for _, validator := range v.validators {
+		if validator.Valid(email) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Package tlsutil holds TLS helpers shared by sso-auth and sso-proxy, both
+// of which support native TLS termination with SNI-selected certificates
+// loaded from a directory.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LoadCertsFromDir reads every <name>.crt/<name>.key pair found directly
+// under dir, parses each certificate's leaf, and returns them ready for use
+// in a tls.Config.
+func LoadCertsFromDir(dir string) ([]tls.Certificate, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no certificates found in %q", dir)
+	}
+
+	certs := make([]tls.Certificate, 0, len(matches))
+	for _, certFile := range matches {
+		keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading cert/key pair %q: %s", certFile, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate %q: %s", certFile, err)
+		}
+		cert.Leaf = leaf
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// GetCertificateFunc builds a tls.Config.GetCertificate callback over every
+// cert/key pair found in dir, so a single listener can serve multiple TLS
+// certificates selected by SNI. It falls back to the first certificate
+// found when the ClientHello carries no matching SNI name.
+func GetCertificateFunc(dir string) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	certs, err := LoadCertsFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		for i := range certs {
+			if hello.SupportsCertificate(&certs[i]) == nil {
+				return &certs[i], nil
+			}
+		}
+		return &certs[0], nil
+	}, nil
+}
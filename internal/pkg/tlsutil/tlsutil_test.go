@@ -0,0 +1,117 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCert(t *testing.T, dir, name, cn string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(100 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %s", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshaling key: %s", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	if err := os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600); err != nil {
+		t.Fatalf("error writing cert: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatalf("error writing key: %s", err)
+	}
+}
+
+func TestLoadCertsFromDirReturnsErrorWhenEmpty(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCertsFromDir(dir); err == nil {
+		t.Error("expected an error loading certs from an empty directory")
+	}
+}
+
+func TestLoadCertsFromDirParsesLeaf(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, "a", "a.example.com")
+
+	certs, err := LoadCertsFromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 cert, got %d", len(certs))
+	}
+	if certs[0].Leaf == nil {
+		t.Error("expected Leaf to be populated")
+	}
+	if certs[0].Leaf.Subject.CommonName != "a.example.com" {
+		t.Errorf("unexpected common name %q", certs[0].Leaf.Subject.CommonName)
+	}
+}
+
+func TestGetCertificateFuncSelectsBySNI(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, "a", "a.example.com")
+	writeCert(t, dir, "b", "b.example.com")
+
+	getCertificate, err := GetCertificateFunc(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	hello := func(serverName string) *tls.ClientHelloInfo {
+		return &tls.ClientHelloInfo{
+			ServerName:        serverName,
+			SupportedVersions: []uint16{tls.VersionTLS12, tls.VersionTLS13},
+			CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+			SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		}
+	}
+
+	cert, err := getCertificate(hello("b.example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cert.Leaf.Subject.CommonName != "b.example.com" {
+		t.Errorf("expected cert for b.example.com, got %q", cert.Leaf.Subject.CommonName)
+	}
+
+	cert, err = getCertificate(hello("unknown.example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cert == nil {
+		t.Error("expected a fallback certificate for an unmatched SNI name")
+	}
+}
@@ -0,0 +1,62 @@
+// Package watchfile provides the fsnotify-based hot-reload loop shared by
+// every sso config type that's watched for changes on disk (the
+// authenticated emails file, htpasswd), so each one doesn't reimplement
+// its own watch goroutine and directory-watch workaround.
+package watchfile
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher calls a reload function once immediately, then again every time
+// the watched path changes on disk, until Stop is called.
+type Watcher struct {
+	fsWatcher *fsnotify.Watcher
+}
+
+// New calls reload once, then starts watching path for changes, calling
+// reload again (ignoring any error it returns, since callers are
+// expected to keep serving their last good state on a failed reload)
+// after each one. path's parent directory is watched rather than path
+// itself, so reload also fires on the atomic rename most editors and
+// orchestration tooling use to update a file in place.
+func New(path string, reload func() error) (*Watcher, error) {
+	if err := reload(); err != nil {
+		return nil, err
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error creating watcher for %q: %s", path, err)
+	}
+	if err := fsWatcher.Add(filepath.Dir(path)); err != nil {
+		fsWatcher.Close()
+		return nil, fmt.Errorf("error watching %q: %s", path, err)
+	}
+
+	w := &Watcher{fsWatcher: fsWatcher}
+	go w.watch(path, reload)
+	return w, nil
+}
+
+func (w *Watcher) watch(path string, reload func() error) {
+	for event := range w.fsWatcher.Events {
+		if filepath.Clean(event.Name) != filepath.Clean(path) {
+			continue
+		}
+		if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+			continue
+		}
+		reload()
+	}
+}
+
+// Stop stops watching the underlying file for changes.
+func (w *Watcher) Stop() {
+	if w.fsWatcher != nil {
+		w.fsWatcher.Close()
+	}
+}
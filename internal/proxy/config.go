@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigFileEnvVar is the env var that points at an optional TOML/YAML/JSON
+// config file. Values it sets are layered underneath env vars: callers
+// should apply LoadConfigFile before envconfig.Process so that any env var
+// still takes precedence over the file.
+const ConfigFileEnvVar = "SSO_CONFIG_FILE"
+
+// LoadConfigFile reads configFile (TOML, YAML, or JSON, auto-detected from
+// its extension) into opts.
+func LoadConfigFile(configFile string, opts *Options) error {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file %q: %s", configFile, err)
+	}
+	if err := v.Unmarshal(opts); err != nil {
+		return fmt.Errorf("error decoding config file %q: %s", configFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/buzzfeed/sso/internal/pkg/cookie"
+)
+
+// CookieStore is the interface OAuthProxy uses to persist session state
+// into a browser cookie. cookie.ChunkedStore is the only implementation:
+// it transparently splits sessions larger than the per-cookie size limit
+// across numbered "<name>_0", "<name>_1", ... cookies instead of silently
+// failing to round-trip them.
+type CookieStore interface {
+	SetCookie(w http.ResponseWriter, base *http.Cookie, value string)
+	GetCookie(req *http.Request, name string) (string, error)
+	ClearCookie(w http.ResponseWriter, base *http.Cookie)
+}
+
+// SetCookieStore configures an OAuthProxy to persist session state through
+// a cookie.ChunkedStore, so a session that outgrows the ~4KB per-cookie
+// limit is chunked instead of silently breaking.
+func SetCookieStore(opts *Options) func(*OAuthProxy) error {
+	return func(p *OAuthProxy) error {
+		p.cookieStore = cookie.NewChunkedStore(0)
+		return nil
+	}
+}
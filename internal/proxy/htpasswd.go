@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/buzzfeed/sso/internal/pkg/htpasswd"
+	log "github.com/buzzfeed/sso/internal/pkg/logging"
+)
+
+// htpasswdMiddleware lets requests carrying an `Authorization: Basic ...`
+// header matching an entry in htpasswdFile bypass the cookie/OAuth flow
+// entirely. On a match it injects X-Forwarded-User/X-Forwarded-Email
+// (email synthesized as user@emailDomain, since htpasswd has no notion of
+// email) into the upstream request and serves next directly; requests
+// without a matching Basic auth credential fall through to next
+// unmodified, so the normal OAuth flow still applies.
+func htpasswdMiddleware(htpasswdFile *htpasswd.HtpasswdFile, emailDomain string, opts *Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, password, ok := req.BasicAuth()
+		if !ok || !htpasswdFile.Validate(user, password) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		email := fmt.Sprintf("%s@%s", user, emailDomain)
+		req.Header.Set("X-Forwarded-User", user)
+		req.Header.Set("X-Forwarded-Email", email)
+
+		if opts.StatsdClient != nil {
+			opts.StatsdClient.Incr("application_auth", []string{"auth_method:htpasswd"}, 1.0)
+		}
+
+		log.NewLogEntry().WithField("user", email).Info("authenticated via htpasswd")
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/buzzfeed/sso/internal/pkg/options"
+)
+
+// RequestSigner signs proxied requests with a configured key so upstreams
+// can verify they came from sso-proxy rather than directly from the
+// internet. The signing scheme itself (which header, which digest) lives
+// in the production proxy engine outside this snapshot; this minimal type
+// exists so SetRequestSigner has a concrete type to configure.
+type RequestSigner struct {
+	key string
+}
+
+// NewRequestSigner returns a RequestSigner for key, which must be
+// non-empty.
+func NewRequestSigner(key string) (*RequestSigner, error) {
+	if key == "" {
+		return nil, fmt.Errorf("request signing key must not be empty")
+	}
+	return &RequestSigner{key: key}, nil
+}
+
+// OAuthProxy holds the per-upstream config New assembles: the session
+// validator, cookie store, optional request signer, upstream routing
+// config, and the reverse-proxy handler for that upstream. The OAuth2
+// sign-in redirect flow and session refresh this stands in front of are
+// part of the production proxy engine outside this snapshot; OAuthProxy
+// exists here so the Set* functional options below have a real type to
+// configure. New applies SetCookieStore, but nothing in this package reads
+// or writes session cookies through cookieStore.
+type OAuthProxy struct {
+	cookieStore    CookieStore
+	validator      options.Validator
+	requestSigner  *RequestSigner
+	upstreamConfig UpstreamConfig
+	proxyHandler   http.Handler
+}
+
+// NewOAuthProxy applies optFuncs to a new OAuthProxy in order.
+func NewOAuthProxy(opts *Options, optFuncs ...func(*OAuthProxy) error) (*OAuthProxy, error) {
+	p := &OAuthProxy{}
+	for _, f := range optFuncs {
+		if err := f(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// SetValidator configures the email validator OAuthProxy checks an
+// authenticated session's email against.
+func SetValidator(v options.Validator) func(*OAuthProxy) error {
+	return func(p *OAuthProxy) error {
+		p.validator = v
+		return nil
+	}
+}
+
+// SetRequestSigner configures the signer OAuthProxy uses on proxied
+// requests, if request signing is enabled.
+func SetRequestSigner(s *RequestSigner) func(*OAuthProxy) error {
+	return func(p *OAuthProxy) error {
+		p.requestSigner = s
+		return nil
+	}
+}
+
+// SetUpstreamConfig configures which upstream this OAuthProxy fronts.
+func SetUpstreamConfig(u UpstreamConfig) func(*OAuthProxy) error {
+	return func(p *OAuthProxy) error {
+		p.upstreamConfig = u
+		return nil
+	}
+}
+
+// SetProxyHandler configures the reverse-proxy handler OAuthProxy serves
+// requests to once a session validates.
+func SetProxyHandler(h http.Handler) func(*OAuthProxy) error {
+	return func(p *OAuthProxy) error {
+		p.proxyHandler = h
+		return nil
+	}
+}
+
+// Handler returns the handler hostRouter should serve this upstream's
+// requests to.
+func (p *OAuthProxy) Handler() http.Handler {
+	return p.proxyHandler
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buzzfeed/sso/internal/pkg/tlsutil"
+)
+
+// StatsdClient is the subset of a statsd client's interface this package
+// needs, so it doesn't have to depend on a concrete statsd library.
+type StatsdClient interface {
+	Incr(stat string, tags []string, rate float64) error
+}
+
+// Options are the config options sso-proxy reads from env vars (via
+// envconfig) and, optionally, a TOML/YAML/JSON file layered underneath
+// them (via LoadConfigFile). Upstream routing config (UpstreamConfig and
+// the Route types) is documented in route.go.
+//
+// RequestSigningKey - string - hex-encoded key used to sign proxied requests so upstreams can verify they came from sso-proxy
+// EmailAddresses - []string - explicit allowlist of emails permitted to authenticate; EmailDomains is used instead when this is empty
+// EmailDomains - []string - email domains permitted to authenticate
+// AuthenticatedEmailsFile - string - path to a newline-delimited email allowlist, hot-reloaded on change and OR'd with EmailAddresses/Domains
+// HtpasswdFile - string - path to a standard htpasswd file; matching Basic-auth credentials bypass the OAuth flow entirely
+// TLSCertFile - string - path to a TLS certificate to terminate TLS natively
+// TLSKeyFile - string - path to the private key matching TLSCertFile
+// TLSCertDir - string - path to a directory of cert/key pairs (each <name>.crt with a matching <name>.key) to serve via SNI, as an alternative to TLSCertFile/TLSKeyFile
+// HTTPSPort - int - port to serve TLS on when TLSCertFile/TLSKeyFile or TLSCertDir are set; Port continues to serve plain HTTP unless RedirectHTTPToHTTPS is set
+// RedirectHTTPToHTTPS - bool (default false) - when TLS is configured, answer every request on Port with a 301 redirect to the same path on HTTPSPort instead of serving it in the clear
+type Options struct {
+	Port            int           `mapstructure:"port" envconfig:"PORT"`
+	TCPReadTimeout  time.Duration `mapstructure:"tcp_read_timeout" envconfig:"TCP_READ_TIMEOUT"`
+	TCPWriteTimeout time.Duration `mapstructure:"tcp_write_timeout" envconfig:"TCP_WRITE_TIMEOUT"`
+	RequestLogging  bool          `mapstructure:"request_logging" envconfig:"REQUEST_LOGGING"`
+
+	RequestSigningKey string `mapstructure:"request_signing_key" envconfig:"REQUEST_SIGNING_KEY"`
+
+	EmailAddresses []string `mapstructure:"email_addresses" envconfig:"EMAIL_ADDRESSES"`
+	EmailDomains   []string `mapstructure:"email_domains" envconfig:"EMAIL_DOMAINS"`
+
+	AuthenticatedEmailsFile string `mapstructure:"authenticated_emails_file" envconfig:"AUTHENTICATED_EMAILS_FILE"`
+	HtpasswdFile            string `mapstructure:"htpasswd_file" envconfig:"HTPASSWD_FILE"`
+
+	TLSCertFile string `mapstructure:"tls_cert_file" envconfig:"TLS_CERT_FILE"`
+	TLSKeyFile  string `mapstructure:"tls_key_file" envconfig:"TLS_KEY_FILE"`
+	TLSCertDir  string `mapstructure:"tls_cert_dir" envconfig:"TLS_CERT_DIR"`
+	HTTPSPort   int    `mapstructure:"https_port" envconfig:"HTTPS_PORT"`
+
+	RedirectHTTPToHTTPS bool `mapstructure:"redirect_http_to_https" envconfig:"REDIRECT_HTTP_TO_HTTPS"`
+
+	StatsdClient StatsdClient `mapstructure:"-" ignored:"true"`
+
+	upstreamConfigs []UpstreamConfig `mapstructure:"upstream_configs" ignored:"true"`
+}
+
+// NewOptions returns Options populated with this package's defaults;
+// LoadConfigFile and envconfig.Process are expected to be applied on top,
+// in that order, same as cmd/sso-proxy/main.go does.
+func NewOptions() *Options {
+	return &Options{
+		Port:            4180,
+		TCPReadTimeout:  30 * time.Second,
+		TCPWriteTimeout: 30 * time.Second,
+		RequestLogging:  true,
+	}
+}
+
+// Validate checks that Options is internally consistent, in particular
+// that any configured TLS cert/key pair or cert dir actually exists and
+// parses.
+func (o *Options) Validate() error {
+	msgs := make([]string, 0)
+
+	if o.TLSCertFile != "" || o.TLSKeyFile != "" || o.TLSCertDir != "" {
+		if o.TLSCertFile != "" || o.TLSKeyFile != "" {
+			if o.TLSCertFile == "" || o.TLSKeyFile == "" {
+				msgs = append(msgs, "both tls-cert-file and tls-key-file must be set together")
+			} else if _, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile); err != nil {
+				msgs = append(msgs, fmt.Sprintf("error loading tls-cert-file/tls-key-file: %s", err))
+			}
+		}
+		if o.TLSCertDir != "" {
+			if _, err := tlsutil.LoadCertsFromDir(o.TLSCertDir); err != nil {
+				msgs = append(msgs, fmt.Sprintf("error loading tls-cert-dir %q: %s", o.TLSCertDir, err))
+			}
+		}
+		if o.HTTPSPort == 0 {
+			msgs = append(msgs, "https-port must be set when TLS is configured")
+		}
+	}
+
+	if len(msgs) != 0 {
+		return fmt.Errorf("Invalid configuration:\n  %s", strings.Join(msgs, "\n  "))
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/buzzfeed/sso/internal/pkg/hostmux"
+	"github.com/buzzfeed/sso/internal/pkg/htpasswd"
+	log "github.com/buzzfeed/sso/internal/pkg/logging"
 	"github.com/buzzfeed/sso/internal/pkg/options"
 	"github.com/buzzfeed/sso/internal/pkg/ping"
 )
@@ -27,10 +29,51 @@ func New(opts *Options) (*SSOProxy, error) {
 		optFuncs = append(optFuncs, SetRequestSigner(requestSigner))
 	}
 
+	var validator options.Validator
 	if len(opts.EmailAddresses) != 0 {
-		optFuncs = append(optFuncs, SetValidator(options.NewEmailAddressValidator(opts.EmailAddresses)))
+		validator = options.NewEmailAddressValidator(opts.EmailAddresses)
 	} else {
-		optFuncs = append(optFuncs, SetValidator(options.NewEmailDomainValidator(opts.EmailDomains)))
+		validator = options.NewEmailDomainValidator(opts.EmailDomains)
+	}
+
+	if opts.AuthenticatedEmailsFile != "" {
+		emailListValidator, err := options.NewEmailListValidator(opts.AuthenticatedEmailsFile,
+			func(count int, loadErr error) {
+				logger := log.NewLogEntry()
+				tags := []string{fmt.Sprintf("result:%t", loadErr == nil)}
+				opts.StatsdClient.Incr("authenticated_emails_file.reload", tags, 1.0)
+				if loadErr != nil {
+					logger.Error(loadErr, "error reloading authenticated emails file")
+					return
+				}
+				logger.WithField("count", count).Info("reloaded authenticated emails file")
+			})
+		if err != nil {
+			return nil, err
+		}
+		validator = options.NewOrValidator(validator, emailListValidator)
+	}
+
+	optFuncs = append(optFuncs, SetValidator(validator))
+
+	var htpasswdFile *htpasswd.HtpasswdFile
+	if opts.HtpasswdFile != "" {
+		htpasswdFile, err = htpasswd.NewHtpasswdFile(opts.HtpasswdFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading htpasswd-file: %s", err)
+		}
+	}
+
+	emailDomain := "localhost"
+	if len(opts.EmailDomains) > 0 {
+		emailDomain = opts.EmailDomains[0]
+	}
+
+	withHtpasswd := func(h http.Handler) http.Handler {
+		if htpasswdFile == nil {
+			return h
+		}
+		return htpasswdMiddleware(htpasswdFile, emailDomain, opts, h)
 	}
 
 	hostRouter := hostmux.NewRouter()
@@ -62,9 +105,9 @@ func New(opts *Options) (*SSOProxy, error) {
 
 		switch route := upstreamConfig.Route.(type) {
 		case *SimpleRoute:
-			hostRouter.HandleStatic(route.FromURL.Host, oauthproxy.Handler())
+			hostRouter.HandleStatic(route.FromURL.Host, withHtpasswd(oauthproxy.Handler()))
 		case *RewriteRoute:
-			hostRouter.HandleRegexp(route.FromRegex, oauthproxy.Handler())
+			hostRouter.HandleRegexp(route.FromRegex, withHtpasswd(oauthproxy.Handler()))
 		default:
 			return nil, fmt.Errorf("unknown route type")
 		}
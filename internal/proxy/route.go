@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// Route is implemented by SimpleRoute and RewriteRoute, the two ways an
+// UpstreamConfig can map a public hostname onto an upstream.
+type Route interface {
+	isRoute()
+}
+
+// SimpleRoute proxies every request for FromURL.Host directly to ToURL.
+type SimpleRoute struct {
+	FromURL *url.URL
+	ToURL   *url.URL
+}
+
+func (*SimpleRoute) isRoute() {}
+
+// RewriteRoute proxies requests whose host matches FromRegex to ToURL,
+// which may reference capture groups from FromRegex.
+type RewriteRoute struct {
+	FromRegex *regexp.Regexp
+	ToURL     *url.URL
+}
+
+func (*RewriteRoute) isRoute() {}
+
+// UpstreamConfig is one entry of Options.upstreamConfigs: a single
+// upstream's routing rule plus the per-upstream settings (cookie name,
+// auth requirements, header passthrough) the production proxy engine
+// reads alongside it.
+type UpstreamConfig struct {
+	Route Route
+}